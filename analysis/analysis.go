@@ -0,0 +1,150 @@
+// Package analysis turns raw document text into the index terms the parent
+// package's SearchEngine stores, through a pluggable, composable Analyzer
+// pipeline instead of a single hard-coded regex.
+package analysis
+
+import (
+	"bufio"
+	"io"
+	"iter"
+	"unicode"
+)
+
+// Analyzer turns the text read from r into a sequence of index terms. ID
+// must return a stable identifier for the exact analysis performed, so a
+// SearchEngine can persist it alongside its index (see the parent package's
+// SaveTo/LoadFrom) and refuse to mix terms produced by different analyzers.
+type Analyzer interface {
+	Tokenize(r io.Reader) iter.Seq[string]
+	ID() string
+}
+
+// Stage transforms a sequence of tokens into another, e.g. lowercasing them
+// or dropping stopwords. Chain composes a tokenizer with zero or more
+// stages.
+type Stage func(iter.Seq[string]) iter.Seq[string]
+
+// Chain is an Analyzer built from a tokenizer followed by a list of Stages,
+// applied in order. Use NewDefaultAnalyzer for the standard
+// Unicode/lowercase/stopword/stem pipeline, or build a custom one with
+// NewChain.
+type Chain struct {
+	id       string
+	tokenize func(io.Reader) iter.Seq[string]
+	stages   []Stage
+}
+
+// NewChain builds an Analyzer from tokenize followed by stages, identified
+// by id. id is persisted in the on-disk index, so it should change whenever
+// tokenize or stages change in a way that would produce different terms.
+func NewChain(id string, tokenize func(io.Reader) iter.Seq[string], stages ...Stage) *Chain {
+	return &Chain{id: id, tokenize: tokenize, stages: stages}
+}
+
+func (c *Chain) ID() string { return c.id }
+
+func (c *Chain) Tokenize(r io.Reader) iter.Seq[string] {
+	tokens := c.tokenize(r)
+	for _, stage := range c.stages {
+		tokens = stage(tokens)
+	}
+	return tokens
+}
+
+// DefaultAnalyzerID identifies the pipeline returned by NewDefaultAnalyzer.
+const DefaultAnalyzerID = "unicode+lower+stopwords+porter2/v1"
+
+// NewDefaultAnalyzer returns the standard analysis pipeline: a Unicode-aware
+// tokenizer, lowercasing, English stopword removal, and Porter2 (snowball)
+// stemming.
+func NewDefaultAnalyzer() *Chain {
+	return NewChain(DefaultAnalyzerID, UnicodeTokenizer, LowercaseFilter, StopwordFilter, PorterStemmer)
+}
+
+// UnicodeTokenizer splits r into maximal runs of letters and digits, using
+// unicode.IsLetter/IsDigit instead of an ASCII-only regex so non-English
+// corpora index correctly.
+func UnicodeTokenizer(r io.Reader) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		reader := bufio.NewReader(r)
+		var token []rune
+		flush := func() bool {
+			if len(token) == 0 {
+				return true
+			}
+			ok := yield(string(token))
+			token = token[:0]
+			return ok
+		}
+		for {
+			ch, _, err := reader.ReadRune()
+			if err != nil {
+				flush()
+				return
+			}
+			if unicode.IsLetter(ch) || unicode.IsDigit(ch) {
+				token = append(token, ch)
+				continue
+			}
+			// Allow an apostrophe or hyphen in the middle of a word (e.g.
+			// "don't", "well-known"), mirroring the previous ASCII regex.
+			if (ch == '\'' || ch == '-') && len(token) > 0 {
+				next, _, err := reader.ReadRune()
+				if err == nil && (unicode.IsLetter(next) || unicode.IsDigit(next)) {
+					token = append(token, ch, next)
+					continue
+				}
+				if err == nil {
+					reader.UnreadRune()
+				}
+			}
+			if !flush() {
+				return
+			}
+		}
+	}
+}
+
+// LowercaseFilter lowercases every token.
+func LowercaseFilter(tokens iter.Seq[string]) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for token := range tokens {
+			if !yield(toLower(token)) {
+				return
+			}
+		}
+	}
+}
+
+// StopwordFilter drops tokens found in Stopwords.
+func StopwordFilter(tokens iter.Seq[string]) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for token := range tokens {
+			if _, stop := Stopwords[token]; stop {
+				continue
+			}
+			if !yield(token) {
+				return
+			}
+		}
+	}
+}
+
+// PorterStemmer reduces every token to its Porter2 (English snowball) stem.
+func PorterStemmer(tokens iter.Seq[string]) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for token := range tokens {
+			if !yield(Stem(token)) {
+				return
+			}
+		}
+	}
+}
+
+func toLower(s string) string {
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		out = append(out, unicode.ToLower(r))
+	}
+	return string(out)
+}
@@ -0,0 +1,327 @@
+package analysis
+
+// invariants lists words the ordinary Porter2 rules would otherwise stem
+// incorrectly, most famously turning "sky" into "ski" via step1c's
+// trailing-y-to-i rule. Per the original Porter algorithm description,
+// these are left unchanged instead of run through the usual steps.
+var invariants = map[string]struct{}{
+	"sky": {}, "news": {}, "howe": {}, "atlas": {}, "cosmos": {}, "bias": {}, "andes": {},
+}
+
+// Stem reduces word to its Porter2 (English snowball) stem, as used by
+// PorterStemmer. Only plain lowercase ASCII words are stemmed; anything else
+// (numbers, the apostrophes/hyphens UnicodeTokenizer allows mid-token, non-
+// ASCII letters) is returned unchanged, since the algorithm is defined in
+// terms of the 26-letter English alphabet. invariants are also returned
+// unchanged.
+func Stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	if _, ok := invariants[word]; ok {
+		return word
+	}
+	w := []byte(word)
+	for _, b := range w {
+		if b < 'a' || b > 'z' {
+			return word
+		}
+	}
+
+	w = step0(w)
+	r1, r2 := regions(w)
+	w = step1a(w)
+	w = step1b(w, r1)
+	w = step1c(w)
+	w = step2(w, r1)
+	w = step3(w, r1, r2)
+	w = step4(w, r2)
+	w = step5(w, r1, r2)
+	return string(w)
+}
+
+func isVowel(c byte) bool {
+	switch c {
+	case 'a', 'e', 'i', 'o', 'u':
+		return true
+	}
+	return false
+}
+
+// isVowelAt reports whether w[i] counts as a vowel, treating y as a vowel
+// when preceded by a consonant and as a consonant otherwise (including when
+// it is the first letter).
+func isVowelAt(w []byte, i int) bool {
+	if isVowel(w[i]) {
+		return true
+	}
+	if w[i] == 'y' {
+		return i > 0 && !isVowelAt(w, i-1)
+	}
+	return false
+}
+
+func endsWith(w []byte, suffix string) bool {
+	return len(w) >= len(suffix) && string(w[len(w)-len(suffix):]) == suffix
+}
+
+func hasPrefix(w []byte, prefix string) bool {
+	return len(w) >= len(prefix) && string(w[:len(prefix)]) == prefix
+}
+
+// inRegion reports whether suffix lies entirely within the region starting
+// at index from (R1 or R2, per regions).
+func inRegion(w []byte, from int, suffix string) bool {
+	return len(w)-len(suffix) >= from
+}
+
+func containsVowel(w []byte) bool {
+	for i := range w {
+		if isVowelAt(w, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// regions computes R1 and R2 as defined by the Porter2 algorithm: R1 is the
+// region after the first non-vowel following a vowel (with a handful of
+// hard-coded exceptional prefixes), and R2 is the same construction applied
+// again within R1.
+func regions(w []byte) (r1, r2 int) {
+	switch {
+	case hasPrefix(w, "gener"):
+		r1 = 5
+	case hasPrefix(w, "commun"):
+		r1 = 6
+	case hasPrefix(w, "arsen"):
+		r1 = 5
+	default:
+		r1 = regionAfterFirstVowelConsonant(w, 0)
+	}
+	r2 = regionAfterFirstVowelConsonant(w, r1)
+	return r1, r2
+}
+
+func regionAfterFirstVowelConsonant(w []byte, from int) int {
+	i := from
+	for i < len(w) && !isVowelAt(w, i) {
+		i++
+	}
+	if i >= len(w) {
+		return len(w)
+	}
+	i++
+	for i < len(w) && isVowelAt(w, i) {
+		i++
+	}
+	if i >= len(w) {
+		return len(w)
+	}
+	return i + 1
+}
+
+// endsInShortSyllable implements the Porter2 "short syllable" test: either a
+// vowel at the start of the word followed by a non-vowel, or a non-vowel,
+// vowel, non-vowel (other than w, x or y) anywhere at the end of the word.
+func endsInShortSyllable(w []byte) bool {
+	n := len(w)
+	if n < 2 {
+		return false
+	}
+	if n == 2 {
+		return isVowelAt(w, 0) && !isVowelAt(w, 1)
+	}
+	last := w[n-1]
+	return !isVowelAt(w, n-3) && isVowelAt(w, n-2) && !isVowelAt(w, n-1) &&
+		last != 'w' && last != 'x' && last != 'y'
+}
+
+// isShortWord reports whether w is "short": it ends in a short syllable and
+// has no R1 region at all.
+func isShortWord(w []byte) bool {
+	if !endsInShortSyllable(w) {
+		return false
+	}
+	r1, _ := regions(w)
+	return r1 >= len(w)
+}
+
+// step0 removes a trailing possessive: 's', 's, or a bare trailing '.
+func step0(w []byte) []byte {
+	for _, suffix := range []string{"'s'", "'s", "'"} {
+		if endsWith(w, suffix) {
+			return w[:len(w)-len(suffix)]
+		}
+	}
+	return w
+}
+
+func step1a(w []byte) []byte {
+	switch {
+	case endsWith(w, "sses"):
+		return append(w[:len(w)-4:len(w)-4], 's', 's')
+	case endsWith(w, "ied"), endsWith(w, "ies"):
+		stem := w[:len(w)-3]
+		if len(stem) > 1 {
+			return append(stem[:len(stem):len(stem)], 'i')
+		}
+		return append(stem[:len(stem):len(stem)], 'i', 'e')
+	case endsWith(w, "us"), endsWith(w, "ss"):
+		return w
+	case endsWith(w, "s"):
+		stem := w[:len(w)-1]
+		// Delete the s only if the word part before it contains a vowel
+		// that isn't the letter immediately preceding the s.
+		if len(w) >= 3 && containsVowel(w[:len(w)-2]) {
+			return stem
+		}
+		return w
+	}
+	return w
+}
+
+func step1b(w []byte, r1 int) []byte {
+	if endsWith(w, "eedly") {
+		if inRegion(w, r1, "eedly") {
+			return append(w[:len(w)-5:len(w)-5], 'e', 'e')
+		}
+		return w
+	}
+	if endsWith(w, "eed") {
+		if inRegion(w, r1, "eed") {
+			return append(w[:len(w)-3:len(w)-3], 'e', 'e')
+		}
+		return w
+	}
+
+	for _, suffix := range []string{"ingly", "edly", "ing", "ed"} {
+		if endsWith(w, suffix) {
+			stem := w[:len(w)-len(suffix)]
+			if !containsVowel(stem) {
+				return w
+			}
+			return step1bCleanup(stem)
+		}
+	}
+	return w
+}
+
+func step1bCleanup(stem []byte) []byte {
+	if endsWith(stem, "at") || endsWith(stem, "bl") || endsWith(stem, "iz") {
+		return append(stem[:len(stem):len(stem)], 'e')
+	}
+	n := len(stem)
+	if n >= 2 && stem[n-1] == stem[n-2] && !isVowelAt(stem, n-1) &&
+		!endsWith(stem, "ll") && !endsWith(stem, "ss") && !endsWith(stem, "zz") {
+		return stem[:n-1]
+	}
+	if isShortWord(stem) {
+		return append(stem[:len(stem):len(stem)], 'e')
+	}
+	return stem
+}
+
+func step1c(w []byte) []byte {
+	n := len(w)
+	if n > 2 && w[n-1] == 'y' && !isVowelAt(w, n-2) {
+		cp := make([]byte, n)
+		copy(cp, w)
+		cp[n-1] = 'i'
+		return cp
+	}
+	return w
+}
+
+type stemRule struct {
+	suffix      string
+	replacement string
+}
+
+func apply(w []byte, r int, rules []stemRule) ([]byte, bool) {
+	for _, rule := range rules {
+		if endsWith(w, rule.suffix) && inRegion(w, r, rule.suffix) {
+			result := append(w[:len(w)-len(rule.suffix):len(w)-len(rule.suffix)], rule.replacement...)
+			return result, true
+		}
+	}
+	return w, false
+}
+
+var step2Rules = []stemRule{
+	{"ization", "ize"}, {"ational", "ate"}, {"fulness", "ful"}, {"ousness", "ous"}, {"iveness", "ive"},
+	{"biliti", "ble"}, {"lessli", "less"}, {"tional", "tion"},
+	{"aliti", "al"}, {"alism", "al"}, {"entli", "ent"}, {"ousli", "ous"}, {"iviti", "ive"}, {"ation", "ate"},
+	{"abli", "able"}, {"alli", "al"}, {"enci", "ence"}, {"anci", "ance"}, {"izer", "ize"}, {"ator", "ate"},
+	{"eli", "e"},
+}
+
+func step2(w []byte, r1 int) []byte {
+	if result, ok := apply(w, r1, step2Rules); ok {
+		return result
+	}
+	if endsWith(w, "ogi") && len(w) >= 4 && w[len(w)-4] == 'l' && inRegion(w, r1, "ogi") {
+		return append(w[:len(w)-3:len(w)-3], 'o', 'g')
+	}
+	if endsWith(w, "li") && len(w) >= 3 && isValidLiEnding(w[len(w)-3]) && inRegion(w, r1, "li") {
+		return w[:len(w)-2]
+	}
+	return w
+}
+
+func isValidLiEnding(c byte) bool {
+	switch c {
+	case 'c', 'd', 'e', 'g', 'h', 'k', 'm', 'n', 'r', 't':
+		return true
+	}
+	return false
+}
+
+var step3Rules = []stemRule{
+	{"ational", "ate"}, {"tional", "tion"}, {"alize", "al"}, {"icate", "ic"}, {"iciti", "ic"},
+	{"ical", "ic"}, {"ness", ""}, {"ful", ""},
+}
+
+func step3(w []byte, r1, r2 int) []byte {
+	if result, ok := apply(w, r1, step3Rules); ok {
+		return result
+	}
+	if endsWith(w, "ative") && inRegion(w, r2, "ative") {
+		return w[:len(w)-5]
+	}
+	return w
+}
+
+var step4Rules = []stemRule{
+	{"ement", ""},
+	{"able", ""}, {"ible", ""}, {"ance", ""}, {"ence", ""}, {"ment", ""},
+	{"ant", ""}, {"ent", ""}, {"ism", ""}, {"ate", ""}, {"iti", ""}, {"ous", ""}, {"ive", ""}, {"ize", ""},
+	{"al", ""}, {"er", ""}, {"ic", ""},
+}
+
+func step4(w []byte, r2 int) []byte {
+	if result, ok := apply(w, r2, step4Rules); ok {
+		return result
+	}
+	if endsWith(w, "ion") && len(w) >= 4 && inRegion(w, r2, "ion") {
+		if prev := w[len(w)-4]; prev == 's' || prev == 't' {
+			return w[:len(w)-3]
+		}
+	}
+	return w
+}
+
+func step5(w []byte, r1, r2 int) []byte {
+	if n := len(w); n > 0 && w[n-1] == 'e' {
+		if inRegion(w, r2, "e") {
+			return w[:n-1]
+		}
+		if inRegion(w, r1, "e") && !endsInShortSyllable(w[:n-1]) {
+			return w[:n-1]
+		}
+	}
+	if n := len(w); n > 1 && w[n-1] == 'l' && w[n-2] == 'l' && inRegion(w, r2, "l") {
+		return w[:n-1]
+	}
+	return w
+}
@@ -0,0 +1,58 @@
+package analysis
+
+import "testing"
+
+func TestStem(t *testing.T) {
+	cases := []struct {
+		word string
+		want string
+	}{
+		{"caresses", "caress"},
+		{"ponies", "poni"},
+		{"ties", "tie"},
+		{"caress", "caress"},
+		{"cats", "cat"},
+		{"feed", "feed"},
+		{"agreed", "agre"},
+		{"plastered", "plaster"},
+		{"bled", "bled"},
+		{"motoring", "motor"},
+		{"sing", "sing"},
+		{"conflated", "conflat"},
+		{"troubled", "troubl"},
+		{"sized", "size"},
+		{"hopping", "hop"},
+		{"tanned", "tan"},
+		{"falling", "fall"},
+		{"hissing", "hiss"},
+		{"fizzed", "fizz"},
+		{"failing", "fail"},
+		{"filing", "file"},
+		{"happy", "happi"},
+		{"relational", "relat"},
+		{"conditional", "condit"},
+		{"rational", "ration"},
+		{"national", "nation"},
+		{"organizer", "organ"},
+		{"generalization", "general"},
+		{"nation", "nation"},
+		{"running", "run"},
+		{"runner", "runner"},
+		// Invariant words: the ordinary rules would otherwise mangle
+		// these (e.g. step1c turns "sky" into "ski").
+		{"sky", "sky"},
+		{"news", "news"},
+		{"atlas", "atlas"},
+		// Short words and non-ASCII-lowercase input are passed through
+		// unchanged rather than run through the algorithm.
+		{"a", "a"},
+		{"an", "an"},
+		{"don't", "don't"},
+		{"123", "123"},
+	}
+	for _, c := range cases {
+		if got := Stem(c.word); got != c.want {
+			t.Errorf("Stem(%q) = %q, want %q", c.word, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"docindexer/query"
+)
+
+// DefaultSnippetWindow is the number of bytes of context included on each
+// side of a SubstringLookup/PhraseLookup match, unless overridden with
+// SetSnippetWindow.
+const DefaultSnippetWindow = 120
+
+// Match is a single hit produced by SubstringLookup or PhraseLookup.
+type Match struct {
+	Document DocumentID
+	Offset   int
+	Snippet  string
+}
+
+// SetSnippetWindow configures how many bytes of context SubstringLookup and
+// PhraseLookup include on each side of a match.
+func (se *SearchEngine) SetSnippetWindow(window int) {
+	se.snippetWindow = window
+}
+
+// SubstringLookup returns every occurrence of query across all indexed
+// documents, using the per-document suffix array built alongside each
+// document's term frequencies (see Frequencies).
+// Unlike IndexLookup, query does not have to be a single
+// whitespace-delimited token: it can be any substring, including a phrase
+// such as "about to explore". Matching is ASCII-case-insensitive, the same
+// as the analyzer's single-term matching, even though the Snippet on each
+// Match is taken from the document's original, unfolded bytes.
+func (se *SearchEngine) SubstringLookup(query string) []Match {
+	result := make([]Match, 0)
+	for doc := range se.suffix {
+		result = append(result, se.matchesInDocument(doc, query)...)
+	}
+	slices.SortFunc(result, func(a, b Match) int {
+		if a.Document != b.Document {
+			if a.Document < b.Document {
+				return -1
+			}
+			return 1
+		}
+		return a.Offset - b.Offset
+	})
+	return result
+}
+
+// PhraseLookup answers a quoted phrase query such as "about to explore". It
+// first narrows the candidate documents down via IndexLookup on the rarest
+// word in the phrase (the token-level index is much cheaper to consult than
+// scanning every suffix array), then verifies exact byte positions of the
+// full phrase using the suffix array of each candidate. Like SubstringLookup,
+// matching folds ASCII case, so "Brown Fox" matches a document that only
+// contains "brown fox" - the same case-insensitivity the analyzer already
+// gives single-term matches, just applied to the suffix array instead.
+func (se *SearchEngine) PhraseLookup(phrase string) []Match {
+	terms := make([]string, 0)
+	for token := range se.analyzer.Tokenize(strings.NewReader(phrase)) {
+		terms = append(terms, token)
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+
+	rarest := terms[0]
+	rarestCount := len(se.IndexLookup(rarest))
+	for _, term := range terms[1:] {
+		if count := len(se.IndexLookup(term)); count < rarestCount {
+			rarest, rarestCount = term, count
+		}
+	}
+
+	result := make([]Match, 0)
+	for _, doc := range se.IndexLookup(rarest) {
+		result = append(result, se.matchesInDocument(doc, phrase)...)
+	}
+	slices.SortFunc(result, func(a, b Match) int {
+		return a.Offset - b.Offset
+	})
+	return result
+}
+
+// matchesInDocument looks up every occurrence of query in doc's suffix array
+// and turns each byte offset into a Match with a surrounding snippet. The
+// suffix array is built over ASCII-case-folded bytes (see foldASCIICase), so
+// query is folded the same way here to match regardless of case.
+func (se *SearchEngine) matchesInDocument(doc DocumentID, query string) []Match {
+	index, ok := se.suffix[doc]
+	if !ok {
+		return nil
+	}
+
+	offsets := index.Lookup(foldASCIICase([]byte(query)), -1)
+	slices.Sort(offsets)
+
+	raw := se.raw[doc]
+	matches := make([]Match, 0, len(offsets))
+	for _, offset := range offsets {
+		matches = append(matches, Match{
+			Document: doc,
+			Offset:   offset,
+			Snippet:  se.snippet(raw, offset, len(query)),
+		})
+	}
+	return matches
+}
+
+// snippetFor returns a representative snippet of doc for display alongside
+// a Search result for q: the first exact occurrence of one of q's clause
+// terms found via the suffix array, or (since indexed terms are stemmed and
+// so may not occur verbatim in the raw bytes) a snippetWindow-sized prefix
+// of the document if none of them match literally.
+func (se *SearchEngine) snippetFor(doc DocumentID, q query.Query) string {
+	for _, clause := range q.Clauses {
+		if matches := se.matchesInDocument(doc, clause.Term.Text); len(matches) > 0 {
+			return matches[0].Snippet
+		}
+	}
+
+	window := se.snippetWindow
+	if window <= 0 {
+		window = DefaultSnippetWindow
+	}
+	raw := se.raw[doc]
+	if len(raw) > window {
+		raw = raw[:window]
+	}
+	return string(raw)
+}
+
+// snippet returns the bytes of raw within se.snippetWindow bytes on either
+// side of [offset, offset+matchLen).
+func (se *SearchEngine) snippet(raw []byte, offset, matchLen int) string {
+	window := se.snippetWindow
+	if window <= 0 {
+		window = DefaultSnippetWindow
+	}
+
+	start := offset - window
+	if start < 0 {
+		start = 0
+	}
+	end := offset + matchLen + window
+	if end > len(raw) {
+		end = len(raw)
+	}
+	return string(raw[start:end])
+}
+
+// foldASCIICase returns a copy of b with ASCII letters lowercased. Non-ASCII
+// bytes, and b's length, are left untouched, so offsets found against the
+// folded copy still index correctly into the original raw bytes. Used to
+// build each document's suffix array and to fold lookup queries before
+// consulting it, so substring/phrase matching is case-insensitive the same
+// way the analyzer's tokenization already is for single-term matching.
+func foldASCIICase(b []byte) []byte {
+	folded := make([]byte, len(b))
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		folded[i] = c
+	}
+	return folded
+}
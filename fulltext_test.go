@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"docindexer/analysis"
+)
+
+func TestSubstringAndPhraseLookup(t *testing.T) {
+	dir := t.TempDir()
+	mustWriteFile(t, filepath.Join(dir, "a.txt"), "the quick brown fox jumps over the lazy dog")
+	mustWriteFile(t, filepath.Join(dir, "b.txt"), "pack my box with five dozen liquor jugs")
+
+	se, err := ReadDirectory(context.Background(), dir, nil, analysis.NewDefaultAnalyzer(), DefaultReadDirectoryOptions())
+	if err != nil {
+		t.Fatalf("ReadDirectory: %v", err)
+	}
+	docA := DocumentID(filepath.Join(dir, "a.txt"))
+
+	if matches := se.SubstringLookup("brown fox"); len(matches) != 1 || matches[0].Document != docA {
+		t.Errorf(`SubstringLookup("brown fox") = %+v, want a single match in %s`, matches, docA)
+	}
+	if matches := se.SubstringLookup("zzz"); len(matches) != 0 {
+		t.Errorf(`SubstringLookup("zzz") = %+v, want no matches`, matches)
+	}
+
+	if matches := se.PhraseLookup("brown fox"); len(matches) != 1 || matches[0].Document != docA {
+		t.Errorf(`PhraseLookup("brown fox") = %+v, want a single match in %s`, matches, docA)
+	}
+	if matches := se.PhraseLookup("fox brown"); len(matches) != 0 {
+		t.Errorf(`PhraseLookup("fox brown") = %+v, want no matches (wrong order)`, matches)
+	}
+
+	// Phrase and substring matching fold ASCII case the same way the
+	// analyzer folds case for single-term matching.
+	if matches := se.PhraseLookup("Brown Fox"); len(matches) != 1 || matches[0].Document != docA {
+		t.Errorf(`PhraseLookup("Brown Fox") = %+v, want a single match in %s`, matches, docA)
+	}
+	if matches := se.SubstringLookup("BROWN"); len(matches) != 1 || matches[0].Document != docA {
+		t.Errorf(`SubstringLookup("BROWN") = %+v, want a single match in %s`, matches, docA)
+	}
+}
+
+func mustWriteFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,14 +2,23 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"math"
+	"index/suffixarray"
+	"io/fs"
 	"os"
-	"regexp"
-	"slices"
+	"os/signal"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
+	"time"
+
+	"docindexer/analysis"
+	"docindexer/query"
+	"docindexer/ranking"
 )
 
 // DocumentID uniquely identifies a document by its filepath.
@@ -28,218 +37,321 @@ type DocTermFrequency map[string]int
 // It maps filenames to DocTermFrequency maps.
 type CollectionTermFrequency map[string]DocTermFrequency
 
-// SearchEngine represents the search index.
-type SearchEngine map[string]DocTermFrequency
-
-// DocumentFrequencyMapping is a tuple of a DocumentID and the DocTermFrequency associated with that document.
-type DocumentFrequencyMapping struct {
-	document  DocumentID
-	frequency DocTermFrequency
+// DocumentMetadata wraps a document's DocTermFrequency with the bookkeeping
+// needed to skip re-indexing unchanged files (ModTime, Size) and to avoid
+// recomputing the document's total token count on every TermFrequency call
+// (TokenCount). It is the unit of (de)serialization used by SaveTo/LoadFrom.
+type DocumentMetadata struct {
+	Terms      DocTermFrequency
+	ModTime    time.Time
+	Size       int64
+	TokenCount int
 }
 
-var WordRegex = regexp.MustCompile("[a-zA-Z]+(['-][a-zA-Z]+)*")
-
-// IndexLookup returns the set of documents that contain the given term.
-func (se *SearchEngine) IndexLookup(term string) []DocumentID {
-	result := make([]DocumentID, 0)
-	for doc, terms := range *se {
-		_, ok := terms[term]
-		if ok {
-			result = append(result, DocumentID(doc))
-		}
-	}
-	return result
+// SearchEngine represents the search index. Besides the token-level index it
+// keeps the raw bytes of every document and a suffix array over a
+// case-folded copy of those bytes, so substring and phrase queries (see
+// SubstringLookup in fulltext.go) can be answered case-insensitively and
+// without re-reading anything from disk.
+type SearchEngine struct {
+	documents map[DocumentID]DocumentMetadata
+	raw       map[DocumentID][]byte
+	suffix    map[DocumentID]*suffixarray.Index
+
+	// analyzer tokenizes document text and query terms at once, so the two
+	// always agree on what counts as a term. analyzerID mirrors
+	// analyzer.ID() and is what actually gets persisted by SaveTo, since an
+	// analysis.Analyzer itself isn't serializable.
+	analyzer   analysis.Analyzer
+	analyzerID string
+
+	// snippetWindow is the number of bytes of context shown around a
+	// substring match on either side. See SetSnippetWindow.
+	snippetWindow int
 }
 
-// RelevanceLookup returns a list of documents relevant for the given term, ordered from most relevant to least relevant.
-func (se *SearchEngine) RelevanceLookup(term string) ([]DocumentID, error) {
-	docIDs := se.IndexLookup(term)
-
-	type DocumentTfIdfMapping struct {
-		document DocumentID
-		tfidf    float64
-	}
+// NewSearchEngine returns an empty, ready to use SearchEngine using the
+// default analysis pipeline (see analysis.NewDefaultAnalyzer).
+func NewSearchEngine() *SearchEngine {
+	return NewSearchEngineWithAnalyzer(analysis.NewDefaultAnalyzer())
+}
 
-	docs := make([]DocumentTfIdfMapping, 0)
-	for _, docID := range docIDs {
-		tfidf, err := se.CountIdf(term, docID)
-		if err != nil {
-			return nil, err
-		}
-		docs = append(docs, DocumentTfIdfMapping{docID, *tfidf})
+// NewSearchEngineWithAnalyzer returns an empty SearchEngine that tokenizes
+// document text and query terms using analyzer instead of the default
+// pipeline.
+func NewSearchEngineWithAnalyzer(analyzer analysis.Analyzer) *SearchEngine {
+	return &SearchEngine{
+		documents:     make(map[DocumentID]DocumentMetadata),
+		raw:           make(map[DocumentID][]byte),
+		suffix:        make(map[DocumentID]*suffixarray.Index),
+		analyzer:      analyzer,
+		analyzerID:    analyzer.ID(),
+		snippetWindow: DefaultSnippetWindow,
 	}
+}
 
-	slices.SortFunc(docs, func(a, b DocumentTfIdfMapping) int {
-		if a.tfidf < b.tfidf {
-			return -1
-		} else if a.tfidf > b.tfidf {
-			return 1
-		}
-		// Equal tfidf. Tiebreaker on the document ID, that's all we have.
-		if a.document < b.document {
-			return -1
-		} else if a.document > b.document {
-			return 1
-		} else {
-			// docIDs is generated from IndexLookup, which should return a set...
-			panic("The same document is in this list more than once??")
-		}
-	})
+// AnalyzerID returns the identifier of the analyzer that produced this
+// SearchEngine's terms, as persisted by SaveTo.
+func (se *SearchEngine) AnalyzerID() string {
+	return se.analyzerID
+}
 
-	result := make([]DocumentID, 0)
-	for _, mapping := range docs {
-		result = append(result, mapping.document)
+// analyzeTerm runs a single query-time term or phrase word through se's
+// analyzer and returns its first produced token (e.g. stemmed and
+// lowercased), matching how the same word was indexed. ok is false if the
+// analyzer dropped the word entirely, e.g. because it is a stopword.
+func (se *SearchEngine) analyzeTerm(text string) (token string, ok bool) {
+	for token := range se.analyzer.Tokenize(strings.NewReader(text)) {
+		return token, true
 	}
-	return result, nil
+	return "", false
 }
 
-// InverseDocumentFrequency implements the inverse document frequency idf(t, D) for a given term and set of Documents.
-func (se *SearchEngine) InverseDocumentFrequency(term string) float64 {
-	numDocuments := len(*se)
-	numDocumentsContaining := 0
-	for _, doc := range *se {
-		_, ok := doc[term]
-		if ok {
-			numDocumentsContaining += 1
-		}
-	}
-	return math.Log(float64(numDocuments) / float64(numDocumentsContaining))
+// Remove deletes document and all index data derived from it (term
+// frequencies, raw bytes, suffix array) from the SearchEngine. Used to
+// reconcile deletions when re-indexing a directory that has shrunk.
+func (se *SearchEngine) Remove(document DocumentID) {
+	delete(se.documents, document)
+	delete(se.raw, document)
+	delete(se.suffix, document)
 }
 
-// TermFrequency implements the term frequency td(t, d) for a given term and document.
-func (se *SearchEngine) TermFrequency(term string, document DocumentID) (*float64, error) {
-	terms, ok := (*se)[string(document)]
-	if !ok {
-		return nil, errors.New("tried to determine the TermFrequency for a document that does not exist")
-	}
-	termCount, ok := terms[term]
-	if !ok {
-		// No need to error here, 0 is a valid result.
-		result := 0.0
-		return &result, nil
-	}
-	// PERF: We could adjust the DocTermFrequency to hold the count, so that we don't have to recalculate it every time.
-	// Lazy for now, because I don't know if it's needed.
-	total := 0
-	for _, count := range terms {
-		total += count
-	}
-	result := float64(termCount) / float64(total)
-	return &result, nil
+// DocumentFrequencyMapping is a tuple of a DocumentID, the DocumentMetadata
+// computed for it, the raw bytes it was computed from, and the suffix array
+// built over a case-folded copy of those bytes (see foldASCIICase). suffix
+// is built by whichever worker produces the mapping (Frequencies or
+// reuseIfUnchanged), not by the reducer, since suffixarray.New is O(document
+// bytes) and would otherwise serialize the whole pipeline through a single
+// goroutine.
+type DocumentFrequencyMapping struct {
+	document DocumentID
+	metadata DocumentMetadata
+	raw      []byte
+	suffix   *suffixarray.Index
 }
 
-// CountIdf implements tfidf.
-func (se *SearchEngine) CountIdf(term string, document DocumentID) (*float64, error) {
-	td, err := se.TermFrequency(term, document)
-	if err != nil {
-		return nil, err
+// IndexLookup returns the set of documents that contain the given term.
+func (se *SearchEngine) IndexLookup(term string) []DocumentID {
+	result := make([]DocumentID, 0)
+	for doc, metadata := range se.documents {
+		_, ok := metadata.Terms[term]
+		if ok {
+			result = append(result, doc)
+		}
 	}
-	idf := se.InverseDocumentFrequency(term)
-	result := *td * idf
-	return &result, nil
+	return result
 }
 
 // ReduceDocuments is the reducer function of the implemented reducer pattern.
 // It reads documents from a channel and creates a search engine, that is passed back through another channel.
-func ReduceDocuments(documents chan DocumentFrequencyMapping, output chan SearchEngine) {
-	searchEngine := SearchEngine{}
+// Each DocumentFrequencyMapping already carries its own suffix array, computed
+// by the worker that produced it, so the reducer only stitches maps together.
+func ReduceDocuments(documents chan DocumentFrequencyMapping, output chan *SearchEngine, analyzer analysis.Analyzer) {
+	searchEngine := NewSearchEngineWithAnalyzer(analyzer)
 	defer func() { output <- searchEngine }()
 	for document := range documents {
-		searchEngine[string(document.document)] = document.frequency
+		searchEngine.documents[document.document] = document.metadata
+		searchEngine.raw[document.document] = document.raw
+		searchEngine.suffix[document.document] = document.suffix
 	}
 }
 
 // Frequencies calculates the term frequency for a given document.
-// Reads the file from disk using the given DocumentID (= file path), performs all text processing operations, and finally writes the result to the channel.
+// Reads the file from disk using the given DocumentID (= file path), runs it through analyzer, and finally writes the result to the channel.
 // Errors are printed to STDERR, but not communicated to any other part of the program. The WaitGroup (orchestrated by the caller) will ensure that there are no deadlocks.
-func Frequencies(document DocumentID, ch chan DocumentFrequencyMapping) {
+func Frequencies(document DocumentID, analyzer analysis.Analyzer, ch chan DocumentFrequencyMapping) {
 	// LABEL ReadFile
-	// Read file into array of lines.
-	file, err := os.Open(string(document))
+	info, err := os.Stat(string(document))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading %s: %s", document, err)
 		return
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	lines := make([]string, 0)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	raw, err := os.ReadFile(string(document))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %s", document, err)
+		return
 	}
 
 	// LABEL Split into words and count them.
 	var wordCounts DocTermFrequency = make(map[string]int)
-	for _, line := range lines {
-		words := WordRegex.FindAllString(line, -1)
-		for _, word := range words {
-			lowercaseWord := strings.ToLower(word)
-			if count, ok := wordCounts[lowercaseWord]; ok {
-				wordCounts[lowercaseWord] = count + 1
-			} else {
-				wordCounts[lowercaseWord] = 1
-			}
-		}
+	tokenCount := 0
+	for token := range analyzer.Tokenize(bytes.NewReader(raw)) {
+		wordCounts[token]++
+		tokenCount++
+	}
+
+	metadata := DocumentMetadata{
+		Terms:      wordCounts,
+		ModTime:    info.ModTime(),
+		Size:       info.Size(),
+		TokenCount: tokenCount,
 	}
 
 	// LABEL PublishDocumentFrequencyMapping
-	// Push the computed mapping to the channel.
-	ch <- DocumentFrequencyMapping{document, wordCounts}
+	// Push the computed mapping, suffix array included, to the channel. The
+	// suffix array is built over a case-folded copy of raw (see
+	// foldASCIICase) so SubstringLookup/PhraseLookup match regardless of
+	// case; raw itself is kept as-is for snippet extraction.
+	ch <- DocumentFrequencyMapping{document, metadata, raw, suffixarray.New(foldASCIICase(raw))}
 }
 
-func FindFiles(directory string) ([]DocumentID, error) {
-	entries, err := os.ReadDir(directory)
-
-	documents := make([]DocumentID, 0)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, entry := range entries {
-		entryPath := directory + string(os.PathSeparator) + entry.Name()
-		if entry.IsDir() {
-			subdirEntries, err := FindFiles(entryPath)
+// walkFiles walks directory in its own goroutine, streaming every regular
+// file it finds into the returned channel, except skip (if non-empty),
+// which callers use to keep their own snapshot file out of its own index.
+// Walking (and therefore the channel) stops early if ctx is cancelled;
+// either a cancellation or a WalkDir failure (e.g. a permission error) is
+// reported on the returned error channel, which always receives exactly one
+// value (possibly nil) before closing.
+func walkFiles(ctx context.Context, directory, skip string) (<-chan DocumentID, <-chan error) {
+	paths := make(chan DocumentID)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(paths)
+		err := filepath.WalkDir(directory, func(path string, d fs.DirEntry, err error) error {
 			if err != nil {
-				return nil, err
+				return err
 			}
-			documents = append(documents, subdirEntries...)
-		} else {
-			documents = append(documents, DocumentID(entryPath))
-		}
+			if d.IsDir() {
+				return nil
+			}
+			if skip != "" && filepath.Clean(path) == skip {
+				return nil
+			}
+			select {
+			case paths <- DocumentID(path):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		errs <- err
+		close(errs)
+	}()
+	return paths, errs
+}
+
+// reuseIfUnchanged reports whether document's on-disk ModTime and size still
+// match what previous indexed it as under the same analyzer, and if so
+// returns the cached mapping so the caller can skip re-running Frequencies
+// on it. A change of analyzer forces every document to be re-tokenized,
+// since its cached terms would otherwise silently disagree with fresh ones.
+func reuseIfUnchanged(previous *SearchEngine, analyzer analysis.Analyzer, document DocumentID) (DocumentFrequencyMapping, bool) {
+	if previous == nil || previous.analyzerID != analyzer.ID() {
+		return DocumentFrequencyMapping{}, false
+	}
+	metadata, ok := previous.documents[document]
+	if !ok {
+		return DocumentFrequencyMapping{}, false
+	}
+	info, err := os.Stat(string(document))
+	if err != nil || !info.ModTime().Equal(metadata.ModTime) || info.Size() != metadata.Size {
+		return DocumentFrequencyMapping{}, false
 	}
+	return DocumentFrequencyMapping{document, metadata, previous.raw[document], previous.suffix[document]}, true
+}
 
-	return documents, nil
+// ReadDirectoryOptions tunes the concurrency of ReadDirectory.
+type ReadDirectoryOptions struct {
+	// Workers is the number of goroutines concurrently running Frequencies.
+	// Zero (the default zero value) means runtime.GOMAXPROCS(0).
+	Workers int
+
+	// SkipPath, if non-empty, is a filepath.Clean-ed path excluded from the
+	// walk, e.g. the caller's own on-disk snapshot file, which would
+	// otherwise be indexed as a document if it happens to live under
+	// directory (as it does whenever directory is "." or ends in a
+	// trailing separator).
+	SkipPath string
 }
 
-func ReadDirectory(directory string) (SearchEngine, error) {
-	files, err := FindFiles(directory)
-	if err != nil {
-		return nil, err
+// DefaultReadDirectoryOptions returns the options ReadDirectory falls back
+// to when called with the zero value: one worker per available CPU.
+func DefaultReadDirectoryOptions() ReadDirectoryOptions {
+	return ReadDirectoryOptions{Workers: runtime.GOMAXPROCS(0)}
+}
+
+// ReadDirectory indexes every file under directory using analyzer. If
+// previous is non-nil, files whose ModTime and size haven't changed since
+// previous was built are reused instead of being re-read and re-tokenized;
+// everything else (new or modified files, or any file at all if analyzer
+// differs from previous's) runs through Frequencies as usual. Files that
+// disappeared from directory are naturally absent from the returned
+// SearchEngine, since it is built from scratch from the current directory
+// listing.
+//
+// Indexing runs as a three-stage pipeline: a producer goroutine
+// (walkFiles) streams DocumentIDs from filepath.WalkDir, a fixed pool of
+// opts.Workers goroutines consumes them and runs Frequencies, and the
+// existing ReduceDocuments goroutine folds the results into a
+// SearchEngine. This keeps goroutine and file-descriptor counts bounded
+// regardless of corpus size, unlike a goroutine-per-file fan-out. Passing
+// a cancelled or later-cancelled ctx stops the walk and drains the
+// pipeline early; the partial SearchEngine built so far is still
+// returned, alongside the error that caused the early exit.
+func ReadDirectory(ctx context.Context, directory string, previous *SearchEngine, analyzer analysis.Analyzer, opts ReadDirectoryOptions) (*SearchEngine, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
 	}
-	// XXX: Right now, we read all files synchronously and then dump them all in a channel.
-	// We could also write files into a channel directly and start processing while we're still searching for files,
-	// but we're skipping that for now because finding the files should be very fast (there aren't a lot I guess?)
-	// and it reduces complexity a bit.
+
+	paths, walkErrs := walkFiles(ctx, directory, opts.SkipPath)
 
 	// LABEL CreateDocTermFreqencyChannelAndGoroutines
-	// Create a channel and launch a goroutine for each file, writing results to the channel.
+	// Launch a fixed pool of workers, each pulling files off paths and
+	// writing results to chFrequencies, instead of one goroutine per file.
 	chFrequencies := make(chan DocumentFrequencyMapping)
 	wgFrequencies := new(sync.WaitGroup)
-	for _, file := range files {
+	for range workers {
 		wgFrequencies.Go(func() {
-			Frequencies(file, chFrequencies)
+			for file := range paths {
+				if mapping, ok := reuseIfUnchanged(previous, analyzer, file); ok {
+					chFrequencies <- mapping
+					continue
+				}
+				Frequencies(file, analyzer, chFrequencies)
+			}
 		})
 	}
 
 	// LABEL InitReducer
 	// Initialize a goroutine to read from the channel and aggregate everything into a SearchEngine object.
-	chSearchEngine := make(chan SearchEngine)
-	go ReduceDocuments(chFrequencies, chSearchEngine)
+	chSearchEngine := make(chan *SearchEngine)
+	go ReduceDocuments(chFrequencies, chSearchEngine, analyzer)
 
 	wgFrequencies.Wait()
 	close(chFrequencies)
 
 	searchEngine := <-chSearchEngine
+	return searchEngine, <-walkErrs
+}
+
+// buildSearchEngine loads directory's previous snapshot (if any), indexes
+// directory with it via ReadDirectory, and writes the freshly indexed
+// engine back out as the new snapshot before returning it. Used by both the
+// CLI entry point and the serve subcommand, so they index the same way.
+func buildSearchEngine(ctx context.Context, directory string) (*SearchEngine, error) {
+	// Clean so a trailing separator (e.g. "docs/") can't turn snapshotPath
+	// into "docs/.idx", a path inside the tree being indexed.
+	directory = filepath.Clean(directory)
+	snapshotPath := directory + ".idx"
+	analyzer := analysis.NewDefaultAnalyzer()
+
+	previous, err := LoadFrom(snapshotPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Println("Ignoring existing snapshot, failed to load it: ", err)
+	}
+
+	opts := DefaultReadDirectoryOptions()
+	opts.SkipPath = snapshotPath
+
+	searchEngine, err := ReadDirectory(ctx, directory, previous, analyzer, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the directory: %w", err)
+	}
+
+	if err := searchEngine.SaveTo(snapshotPath); err != nil {
+		fmt.Println("Failed to save the index snapshot: ", err)
+	}
 	return searchEngine, nil
 }
 
@@ -249,15 +361,36 @@ func main() {
 		os.Exit(1)
 	}
 
-	directory := os.Args[1]
+	// Ctrl+C aborts indexing (and, under "serve", shuts the server down)
+	// cleanly instead of leaving goroutines fanned out against a
+	// half-deleted directory or a socket nobody is listening on anymore.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if os.Args[1] == "serve" {
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: docindexer serve DIRECTORY [addr]. Aborting.")
+			os.Exit(1)
+		}
+		addr := ":8080"
+		if len(os.Args) > 3 {
+			addr = os.Args[3]
+		}
+		if err := serve(ctx, os.Args[2], addr); err != nil {
+			fmt.Println("Server failed: ", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	searchEngine, err := ReadDirectory(directory)
+	directory := os.Args[1]
+	searchEngine, err := buildSearchEngine(ctx, directory)
 	if err != nil {
-		fmt.Println("Failed to read the directory: ", err)
+		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	fmt.Println(searchEngine)
+	fmt.Printf("indexed %d documents\n", len(searchEngine.documents))
 
 	// Input read loop by the example of https://stackoverflow.com/a/49715256.
 	cliReader := bufio.NewScanner(os.Stdin)
@@ -266,9 +399,34 @@ func main() {
 		term := cliReader.Text()
 		if len(term) > 0 {
 			fmt.Println("== " + term)
-			// TODO: Search for the term and output documents.
+			printSearchResults(searchEngine, term)
 		} else {
 			break
 		}
 	}
 }
+
+// printSearchResults parses input as a boolean query (see query.Parse) and
+// prints its BM25-ranked matches against se, one per line as
+// "document\tscore\tsnippet". A parse or search failure is reported the
+// same way other CLI errors in main are: printed to stdout, not fatal.
+func printSearchResults(se *SearchEngine, input string) {
+	parsed, err := query.Parse(input)
+	if err != nil {
+		fmt.Println("Invalid query: ", err)
+		return
+	}
+
+	results, err := se.Search(parsed, ranking.DefaultOptions())
+	if err != nil {
+		fmt.Println("Search failed: ", err)
+		return
+	}
+	if len(results) == 0 {
+		fmt.Println("No matches.")
+		return
+	}
+	for _, result := range results {
+		fmt.Printf("%s\t%.4f\t%s\n", result.Document, result.Score, se.snippetFor(result.Document, parsed))
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"docindexer/analysis"
+)
+
+// benchmarkCorpus creates n small files under a fresh temp directory for
+// BenchmarkReadDirectory to index, and returns the directory's path.
+func benchmarkCorpus(b *testing.B, n int) string {
+	b.Helper()
+	dir := b.TempDir()
+	for i := range n {
+		path := filepath.Join(dir, fmt.Sprintf("doc-%d.txt", i))
+		content := fmt.Sprintf("document %d contains a handful of words about searching and indexing.", i)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// benchmarkReadDirectory measures ReadDirectory's throughput over a corpus
+// of n small files, always indexing from scratch (previous is nil).
+func benchmarkReadDirectory(b *testing.B, n int) {
+	dir := benchmarkCorpus(b, n)
+	analyzer := analysis.NewDefaultAnalyzer()
+	opts := DefaultReadDirectoryOptions()
+
+	b.ResetTimer()
+	for range b.N {
+		if _, err := ReadDirectory(context.Background(), dir, nil, analyzer, opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadDirectory1k(b *testing.B)   { benchmarkReadDirectory(b, 1_000) }
+func BenchmarkReadDirectory10k(b *testing.B)  { benchmarkReadDirectory(b, 10_000) }
+func BenchmarkReadDirectory100k(b *testing.B) { benchmarkReadDirectory(b, 100_000) }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/gob"
+	"fmt"
+	"index/suffixarray"
+	"os"
+)
+
+// snapshotVersion is bumped whenever the on-disk layout of snapshot changes,
+// so LoadFrom can refuse to load a file written by an incompatible version
+// instead of decoding it into garbage.
+const snapshotVersion = 1
+
+// snapshot is the gob-encoded representation of a SearchEngine written by
+// SaveTo. The suffix arrays are intentionally not persisted: they are cheap
+// to rebuild from raw on load and gob has no way to encode them directly.
+// AnalyzerID records which analysis.Analyzer produced Documents' terms, so a
+// later LoadFrom/ReadDirectory pair can detect a mismatched analyzer instead
+// of silently mixing terms from two different pipelines.
+type snapshot struct {
+	Version    int
+	AnalyzerID string
+	Documents  map[DocumentID]DocumentMetadata
+	Raw        map[DocumentID][]byte
+}
+
+// SaveTo writes se to path as a gob-encoded snapshot, so a later process can
+// resume from it via LoadFrom instead of re-indexing from scratch.
+func (se *SearchEngine) SaveTo(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	snap := snapshot{
+		Version:    snapshotVersion,
+		AnalyzerID: se.analyzerID,
+		Documents:  se.documents,
+		Raw:        se.raw,
+	}
+	return gob.NewEncoder(file).Encode(snap)
+}
+
+// LoadFrom reads a snapshot previously written by SaveTo and rebuilds a
+// SearchEngine from it, including the suffix arrays that SaveTo leaves out.
+// Callers typically pass the result to ReadDirectory as its previous engine,
+// so unchanged files don't have to be re-tokenized. The returned engine's
+// analyzer is always the default one; if the snapshot was built with a
+// custom analyzer, plug it back in before calling ReadDirectory (its
+// AnalyzerID is preserved either way, so a mismatch is still caught there).
+func LoadFrom(path string) (*SearchEngine, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var snap snapshot
+	if err := gob.NewDecoder(file).Decode(&snap); err != nil {
+		return nil, err
+	}
+	if snap.Version != snapshotVersion {
+		return nil, fmt.Errorf("snapshot %s has version %d, this binary understands version %d", path, snap.Version, snapshotVersion)
+	}
+
+	se := NewSearchEngine()
+	se.analyzerID = snap.AnalyzerID
+	se.documents = snap.Documents
+	se.raw = snap.Raw
+	for document, raw := range se.raw {
+		se.suffix[document] = suffixarray.New(foldASCIICase(raw))
+	}
+	return se, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"docindexer/analysis"
+)
+
+// TestSaveLoadRoundTrip verifies that a SearchEngine survives a
+// SaveTo/LoadFrom round trip, and that a subsequent ReadDirectory run
+// against the loaded snapshot reuses every unchanged document instead of
+// re-reading and re-tokenizing it.
+func TestSaveLoadRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	mustWriteFile(t, filepath.Join(dir, "a.txt"), "the quick brown fox jumps over the lazy dog")
+	mustWriteFile(t, filepath.Join(dir, "b.txt"), "pack my box with five dozen liquor jugs")
+
+	analyzer := analysis.NewDefaultAnalyzer()
+	engine, err := ReadDirectory(context.Background(), dir, nil, analyzer, DefaultReadDirectoryOptions())
+	if err != nil {
+		t.Fatalf("ReadDirectory: %v", err)
+	}
+
+	snapshotPath := filepath.Join(t.TempDir(), "snapshot.idx")
+	if err := engine.SaveTo(snapshotPath); err != nil {
+		t.Fatalf("SaveTo: %v", err)
+	}
+
+	loaded, err := LoadFrom(snapshotPath)
+	if err != nil {
+		t.Fatalf("LoadFrom: %v", err)
+	}
+	if loaded.AnalyzerID() != engine.AnalyzerID() {
+		t.Errorf("AnalyzerID() = %q, want %q", loaded.AnalyzerID(), engine.AnalyzerID())
+	}
+	if len(loaded.documents) != len(engine.documents) {
+		t.Errorf("loaded %d documents, want %d", len(loaded.documents), len(engine.documents))
+	}
+	for doc, metadata := range engine.documents {
+		loadedMetadata, ok := loaded.documents[doc]
+		if !ok {
+			t.Errorf("document %s missing after LoadFrom", doc)
+			continue
+		}
+		if loadedMetadata.TokenCount != metadata.TokenCount {
+			t.Errorf("document %s: TokenCount = %d, want %d", doc, loadedMetadata.TokenCount, metadata.TokenCount)
+		}
+	}
+
+	// Nothing changed on disk, so reindexing against the loaded snapshot
+	// should reuse every document's raw bytes verbatim (reuseIfUnchanged
+	// hands back loaded.raw[doc] itself) rather than re-reading any file.
+	reindexed, err := ReadDirectory(context.Background(), dir, loaded, analyzer, DefaultReadDirectoryOptions())
+	if err != nil {
+		t.Fatalf("ReadDirectory (reindex): %v", err)
+	}
+	for doc := range engine.documents {
+		reused, loadedRaw := reindexed.raw[doc], loaded.raw[doc]
+		if len(reused) == 0 || &reused[0] != &loadedRaw[0] {
+			t.Errorf("document %s was re-read instead of reused from the unchanged snapshot", doc)
+		}
+	}
+}
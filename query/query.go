@@ -0,0 +1,125 @@
+// Package query parses the small boolean query language accepted by the
+// parent package's SearchEngine.Search: whitespace-separated terms, quoted
+// phrases, a trailing ~ for a fuzzy term, and the AND/OR/NOT keywords
+// combining them, e.g. `foo AND bar NOT baz` or `"exact phrase" OR quux~`.
+//
+// The grammar is deliberately flat: clauses are evaluated strictly left to
+// right with no operator precedence and no parenthesized grouping.
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Op identifies how a Clause combines with the result of everything to its
+// left. It is ignored on the first clause of a Query, which has nothing to
+// combine with.
+type Op int
+
+const (
+	OpAnd Op = iota
+	OpOr
+	OpNot
+)
+
+// Term is a single operand in a Query: either a plain word, a quoted phrase,
+// or (if Fuzzy is set) a word that should also match close misspellings.
+type Term struct {
+	Text   string
+	Phrase bool
+	Fuzzy  bool
+}
+
+// Clause is one operand of a Query together with the operator joining it to
+// the previous clause.
+type Clause struct {
+	Op   Op
+	Term Term
+}
+
+// Query is a parsed boolean expression, ready for SearchEngine.Search.
+type Query struct {
+	Clauses []Clause
+}
+
+// Parse parses a boolean query expression. See the package doc comment for
+// the supported syntax.
+func Parse(input string) (Query, error) {
+	tokens := tokenize(input)
+	if len(tokens) == 0 {
+		return Query{}, fmt.Errorf("query: empty expression")
+	}
+
+	var clauses []Clause
+	op := OpAnd
+	expectOperand := true
+	for _, token := range tokens {
+		switch token {
+		case "AND":
+			op, expectOperand = OpAnd, true
+		case "OR":
+			op, expectOperand = OpOr, true
+		case "NOT":
+			op, expectOperand = OpNot, true
+		default:
+			if !expectOperand {
+				return Query{}, fmt.Errorf("query: unexpected %q, expected AND/OR/NOT", token)
+			}
+			if len(clauses) == 0 && op == OpNot {
+				return Query{}, fmt.Errorf("query: expression cannot start with NOT")
+			}
+			term, err := parseTerm(token)
+			if err != nil {
+				return Query{}, err
+			}
+			clauses = append(clauses, Clause{Op: op, Term: term})
+			expectOperand = false
+		}
+	}
+	if expectOperand {
+		return Query{}, fmt.Errorf("query: expression ends with a dangling operator")
+	}
+	return Query{Clauses: clauses}, nil
+}
+
+func parseTerm(token string) (Term, error) {
+	fuzzy := strings.HasSuffix(token, "~")
+	if fuzzy {
+		token = strings.TrimSuffix(token, "~")
+	}
+	if strings.HasPrefix(token, `"`) {
+		if len(token) < 2 || !strings.HasSuffix(token, `"`) {
+			return Term{}, fmt.Errorf("query: unterminated phrase %q", token)
+		}
+		return Term{Text: strings.Trim(token, `"`), Phrase: true, Fuzzy: fuzzy}, nil
+	}
+	return Term{Text: token, Fuzzy: fuzzy}, nil
+}
+
+// tokenize splits input on whitespace, keeping double-quoted phrases intact
+// as a single token (including their quotes, stripped later by parseTerm).
+func tokenize(input string) []string {
+	tokens := make([]string, 0)
+	var current strings.Builder
+	inPhrase := false
+	for _, r := range input {
+		switch {
+		case r == '"':
+			current.WriteRune(r)
+			inPhrase = !inPhrase
+		case unicode.IsSpace(r) && !inPhrase:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
@@ -0,0 +1,60 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		input string
+		want  Query
+	}{
+		{
+			input: "foo",
+			want:  Query{Clauses: []Clause{{Op: OpAnd, Term: Term{Text: "foo"}}}},
+		},
+		{
+			input: "foo AND bar NOT baz",
+			want: Query{Clauses: []Clause{
+				{Op: OpAnd, Term: Term{Text: "foo"}},
+				{Op: OpAnd, Term: Term{Text: "bar"}},
+				{Op: OpNot, Term: Term{Text: "baz"}},
+			}},
+		},
+		{
+			input: `"exact phrase" OR quux~`,
+			want: Query{Clauses: []Clause{
+				{Op: OpAnd, Term: Term{Text: "exact phrase", Phrase: true}},
+				{Op: OpOr, Term: Term{Text: "quux", Fuzzy: true}},
+			}},
+		},
+	}
+	for _, c := range cases {
+		got, err := Parse(c.input)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", c.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", c.input, got, c.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []string{
+		"",
+		"foo AND",
+		`"unterminated`,
+		// A leading NOT has no running result set to subtract from, and
+		// silently matching everything that *contains* the term (the
+		// inverse of what NOT means) is worse than rejecting it outright.
+		"NOT foo",
+	}
+	for _, input := range cases {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", input)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Package ranking implements Okapi BM25 scoring for the document index in
+// the parent package, replacing its earlier raw TF-IDF relevance score.
+package ranking
+
+import "math"
+
+// Options tunes the BM25 formula and lets callers reward matches in
+// particular fields (e.g. a document's filename) beyond its body text.
+type Options struct {
+	// K1 controls term frequency saturation. Higher values let repeated
+	// occurrences of a term keep contributing to the score for longer.
+	K1 float64
+	// B controls how strongly document length is normalized against the
+	// corpus average. 0 disables length normalization entirely, 1 applies
+	// it fully.
+	B float64
+	// FieldBoosts adds extra, IDF-scaled score when a term also matches the
+	// named field. The only field currently consulted by the caller is
+	// "filename".
+	FieldBoosts map[string]float64
+}
+
+// DefaultOptions returns the commonly used BM25 defaults (k1=1.2, b=0.75)
+// with no field boosts configured.
+func DefaultOptions() Options {
+	return Options{K1: 1.2, B: 0.75}
+}
+
+// IDF implements the BM25 inverse document frequency idf(t) for a term that
+// occurs in docFreq of n documents. Unlike the classic ln(N/df) formula,
+// this variant (ln((N-df+0.5)/(df+0.5) + 1)) stays positive and finite even
+// when a term appears in most or all documents.
+func IDF(n, docFreq int) float64 {
+	return math.Log((float64(n)-float64(docFreq)+0.5)/(float64(docFreq)+0.5) + 1)
+}
+
+// TermScore computes a single query term's BM25 contribution to a document's
+// score: termFreq is the term's raw count in the document, docLength is the
+// document's total token count, avgDocLength is the corpus average, and idf
+// is the term's IDF as returned by IDF. avgDocLength of 0 (e.g. a corpus
+// whose documents are all empty or all stopwords) would otherwise divide by
+// zero; length normalization is skipped in that case instead.
+func TermScore(termFreq, docLength int, avgDocLength, idf float64, opts Options) float64 {
+	lengthNorm := 1.0
+	if avgDocLength > 0 {
+		lengthNorm = float64(docLength) / avgDocLength
+	}
+	numerator := float64(termFreq) * (opts.K1 + 1)
+	denominator := float64(termFreq) + opts.K1*(1-opts.B+opts.B*lengthNorm)
+	return idf * numerator / denominator
+}
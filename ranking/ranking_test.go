@@ -0,0 +1,40 @@
+package ranking
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIDF(t *testing.T) {
+	// A term that occurs in every document should still score positive,
+	// unlike the classic ln(N/df) formula (which would give ln(1) = 0) or
+	// ln((N-df+0.5)/(df+0.5)) without the +1 (which goes negative once
+	// df > N/2).
+	if idf := IDF(10, 10); idf <= 0 {
+		t.Errorf("IDF(10, 10) = %v, want > 0", idf)
+	}
+	// A rarer term should score higher than a common one.
+	rare, common := IDF(100, 1), IDF(100, 50)
+	if rare <= common {
+		t.Errorf("IDF(100, 1) = %v, want > IDF(100, 50) = %v", rare, common)
+	}
+}
+
+func TestTermScore(t *testing.T) {
+	opts := DefaultOptions()
+	idf := IDF(10, 2)
+
+	// More occurrences of a term in a document should never lower its
+	// score.
+	low := TermScore(1, 100, 100, idf, opts)
+	high := TermScore(5, 100, 100, idf, opts)
+	if high <= low {
+		t.Errorf("TermScore(5, ...) = %v, want > TermScore(1, ...) = %v", high, low)
+	}
+
+	// A zero average document length (e.g. every document in the corpus
+	// is empty or entirely stopwords) must not produce NaN or Inf.
+	if score := TermScore(1, 0, 0, idf, opts); math.IsNaN(score) || math.IsInf(score, 0) {
+		t.Errorf("TermScore with avgDocLength=0 = %v, want a finite number", score)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"docindexer/query"
+	"docindexer/ranking"
+)
+
+// ScoredDoc is a single result of Search: a document together with its BM25
+// score for the query that produced it.
+type ScoredDoc struct {
+	Document DocumentID
+	Score    float64
+}
+
+// Search evaluates a parsed boolean query against the index and returns the
+// matching documents ordered by descending BM25 score, replacing the
+// single-term RelevanceLookup. Clauses are combined strictly left to right:
+// AND intersects the running result set, OR unions it, and NOT removes
+// whatever the clause matches.
+func (se *SearchEngine) Search(q query.Query, opts ranking.Options) ([]ScoredDoc, error) {
+	if len(q.Clauses) == 0 {
+		return nil, fmt.Errorf("search: query has no clauses")
+	}
+
+	avgDocLength := se.averageDocumentLength()
+
+	var current map[DocumentID]float64
+	for i, clause := range q.Clauses {
+		scores := se.termScores(clause.Term, opts, avgDocLength)
+		if i == 0 {
+			current = scores
+			continue
+		}
+		current = combineScores(current, clause.Op, scores)
+	}
+
+	result := make([]ScoredDoc, 0, len(current))
+	for doc, score := range current {
+		result = append(result, ScoredDoc{doc, score})
+	}
+	slices.SortFunc(result, func(a, b ScoredDoc) int {
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
+			return 1
+		}
+		// Equal score. Tiebreaker on the document ID, that's all we have.
+		if a.Document < b.Document {
+			return -1
+		} else if a.Document > b.Document {
+			return 1
+		}
+		return 0
+	})
+	return result, nil
+}
+
+// combineScores folds a query term's per-document scores into the running
+// result of everything to its left, per op.
+func combineScores(current map[DocumentID]float64, op query.Op, scores map[DocumentID]float64) map[DocumentID]float64 {
+	switch op {
+	case query.OpAnd:
+		result := make(map[DocumentID]float64)
+		for doc, score := range current {
+			if termScore, ok := scores[doc]; ok {
+				result[doc] = score + termScore
+			}
+		}
+		return result
+	case query.OpOr:
+		result := make(map[DocumentID]float64, len(current))
+		for doc, score := range current {
+			result[doc] = score
+		}
+		for doc, termScore := range scores {
+			result[doc] += termScore
+		}
+		return result
+	case query.OpNot:
+		result := make(map[DocumentID]float64, len(current))
+		for doc, score := range current {
+			if _, ok := scores[doc]; !ok {
+				result[doc] = score
+			}
+		}
+		return result
+	default:
+		return current
+	}
+}
+
+// termScores computes the BM25 contribution of a single query term for every
+// document it matches.
+func (se *SearchEngine) termScores(term query.Term, opts ranking.Options, avgDocLength float64) map[DocumentID]float64 {
+	docs := se.matchingDocuments(term)
+	n := len(se.documents)
+
+	result := make(map[DocumentID]float64, len(docs))
+	for _, doc := range docs {
+		metadata := se.documents[doc]
+		idf := ranking.IDF(n, len(docs))
+		score := ranking.TermScore(se.termOccurrences(term, doc), metadata.TokenCount, avgDocLength, idf, opts)
+		if boost, ok := opts.FieldBoosts["filename"]; ok && strings.Contains(strings.ToLower(string(doc)), strings.ToLower(term.Text)) {
+			score += boost * idf
+		}
+		result[doc] = score
+	}
+	return result
+}
+
+// matchingDocuments returns the documents a query term matches: PhraseLookup
+// for a quoted phrase, the union of IndexLookup over every close variant for
+// a fuzzy term, and a plain IndexLookup otherwise.
+func (se *SearchEngine) matchingDocuments(term query.Term) []DocumentID {
+	switch {
+	case term.Phrase:
+		seen := make(DocumentIDs)
+		result := make([]DocumentID, 0)
+		for _, match := range se.PhraseLookup(term.Text) {
+			if _, ok := seen[string(match.Document)]; !ok {
+				seen[string(match.Document)] = struct{}{}
+				result = append(result, match.Document)
+			}
+		}
+		return result
+	case term.Fuzzy:
+		token, ok := se.analyzeTerm(term.Text)
+		if !ok {
+			return nil
+		}
+		seen := make(DocumentIDs)
+		result := make([]DocumentID, 0)
+		for _, variant := range se.fuzzyVariants(token) {
+			for _, doc := range se.IndexLookup(variant) {
+				if _, ok := seen[string(doc)]; !ok {
+					seen[string(doc)] = struct{}{}
+					result = append(result, doc)
+				}
+			}
+		}
+		return result
+	default:
+		token, ok := se.analyzeTerm(term.Text)
+		if !ok {
+			return nil
+		}
+		return se.IndexLookup(token)
+	}
+}
+
+// termOccurrences counts how often term matched within a specific document,
+// for use as BM25's raw term frequency f(t,d).
+func (se *SearchEngine) termOccurrences(term query.Term, document DocumentID) int {
+	switch {
+	case term.Phrase:
+		count := 0
+		for _, match := range se.PhraseLookup(term.Text) {
+			if match.Document == document {
+				count++
+			}
+		}
+		return count
+	case term.Fuzzy:
+		token, ok := se.analyzeTerm(term.Text)
+		if !ok {
+			return 0
+		}
+		count := 0
+		for _, variant := range se.fuzzyVariants(token) {
+			count += se.documents[document].Terms[variant]
+		}
+		return count
+	default:
+		token, ok := se.analyzeTerm(term.Text)
+		if !ok {
+			return 0
+		}
+		return se.documents[document].Terms[token]
+	}
+}
+
+// fuzzyVariants returns every term in the corpus vocabulary within edit
+// distance 1 of target, including target itself if it is present.
+func (se *SearchEngine) fuzzyVariants(target string) []string {
+	seen := make(map[string]struct{})
+	variants := make([]string, 0)
+	for _, metadata := range se.documents {
+		for vocabTerm := range metadata.Terms {
+			if _, ok := seen[vocabTerm]; ok {
+				continue
+			}
+			if levenshtein1(target, vocabTerm) {
+				seen[vocabTerm] = struct{}{}
+				variants = append(variants, vocabTerm)
+			}
+		}
+	}
+	return variants
+}
+
+// levenshtein1 reports whether a and b are equal or exactly one
+// insertion/deletion/substitution apart.
+func levenshtein1(a, b string) bool {
+	if a == b {
+		return true
+	}
+	// Lengths differing by more than one can never be within edit distance 1.
+	if diff := len(a) - len(b); diff > 1 || diff < -1 {
+		return false
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	// len(a) <= len(b) <= len(a)+1 from here on.
+	i, j, mismatches := 0, 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			i++
+			j++
+			continue
+		}
+		mismatches++
+		if mismatches > 1 {
+			return false
+		}
+		if len(a) == len(b) {
+			// Substitution: advance both.
+			i++
+			j++
+		} else {
+			// Insertion into a / deletion from b: advance only b.
+			j++
+		}
+	}
+	return true
+}
+
+// averageDocumentLength is avgdl in the BM25 formula: the mean token count
+// across every indexed document.
+func (se *SearchEngine) averageDocumentLength() float64 {
+	if len(se.documents) == 0 {
+		return 0
+	}
+	total := 0
+	for _, metadata := range se.documents {
+		total += metadata.TokenCount
+	}
+	return float64(total) / float64(len(se.documents))
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"docindexer/query"
+	"docindexer/ranking"
+)
+
+// searchResult is a single hit within a searchResponse.
+type searchResult struct {
+	Document DocumentID `json:"doc"`
+	Score    float64    `json:"score"`
+	Snippet  string     `json:"snippet"`
+}
+
+// searchResponse is the JSON body GET /search returns.
+type searchResponse struct {
+	Results []searchResult            `json:"results"`
+	Facets  map[string]map[string]int `json:"facets"`
+	TookMs  int64                     `json:"took_ms"`
+}
+
+// server exposes a SearchEngine over HTTP: GET /search for ranked, faceted
+// JSON results, GET /doc to fetch a single indexed document's raw bytes, and
+// a minimal HTML search page at /.
+type server struct {
+	engine *SearchEngine
+}
+
+// serve builds a SearchEngine for directory (reusing buildSearchEngine, the
+// same indexing pipeline the plain CLI mode uses) and serves it over HTTP on
+// addr until ctx is cancelled, at which point it shuts down gracefully.
+func serve(ctx context.Context, directory, addr string) error {
+	engine, err := buildSearchEngine(ctx, directory)
+	if err != nil {
+		return err
+	}
+
+	srv := &server{engine: engine}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /search", srv.handleSearch)
+	mux.HandleFunc("GET /doc", srv.handleDoc)
+	mux.HandleFunc("GET /{$}", srv.handleIndex)
+
+	httpServer := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Serving %s on %s\n", directory, addr)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// handleSearch parses the q, limit and offset query parameters, runs the
+// query against s.engine, and writes back the ranked page of results
+// together with an extension facet count over the full (unpaginated)
+// result set.
+func (s *server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		http.Error(w, "missing required query parameter q", http.StatusBadRequest)
+		return
+	}
+	limit := queryInt(r, "limit", 10)
+	if limit <= 0 {
+		// queryInt treats 0 as a valid parse of an explicit "limit=0", but a
+		// page size of zero is never useful to a caller, so it falls back to
+		// the same default as an absent limit rather than truncating every
+		// result away.
+		limit = 10
+	}
+	offset := queryInt(r, "offset", 0)
+
+	parsed, err := query.Parse(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	scored, err := s.engine.Search(parsed, ranking.DefaultOptions())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	facets := make(map[string]int)
+	for _, doc := range scored {
+		facets[filepath.Ext(string(doc.Document))]++
+	}
+
+	page := scored
+	if offset < len(page) {
+		page = page[offset:]
+	} else {
+		page = nil
+	}
+	if limit < len(page) {
+		page = page[:limit]
+	}
+
+	results := make([]searchResult, 0, len(page))
+	for _, doc := range page {
+		results = append(results, searchResult{
+			Document: doc.Document,
+			Score:    doc.Score,
+			Snippet:  s.engine.snippetFor(doc.Document, parsed),
+		})
+	}
+
+	writeJSON(w, searchResponse{
+		Results: results,
+		Facets:  map[string]map[string]int{"extension": facets},
+		TookMs:  time.Since(start).Milliseconds(),
+	})
+}
+
+// handleDoc writes the raw bytes of the document named by the required id
+// query parameter, or 404 if it isn't indexed.
+func (s *server) handleDoc(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "missing required query parameter id", http.StatusBadRequest)
+		return
+	}
+	raw, ok := s.engine.raw[DocumentID(id)]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(raw)
+}
+
+// handleIndex serves a minimal HTML page with a search box that calls
+// GET /search and renders the results, so the indexer is usable without a
+// separate client.
+func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, indexPage)
+}
+
+const indexPage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>docindexer</title></head>
+<body>
+<h1>docindexer</h1>
+<input id="q" type="text" size="60" placeholder='foo AND bar NOT baz, "exact phrase", quux~'>
+<button onclick="search()">Search</button>
+<p id="status"></p>
+<ul id="results"></ul>
+<script>
+async function search() {
+	const q = document.getElementById("q").value;
+	const status = document.getElementById("status");
+	const list = document.getElementById("results");
+	list.innerHTML = "";
+	const res = await fetch("/search?q=" + encodeURIComponent(q));
+	if (!res.ok) {
+		status.textContent = await res.text();
+		return;
+	}
+	const data = await res.json();
+	status.textContent = data.results.length + " results in " + data.took_ms + "ms";
+	for (const r of data.results) {
+		const li = document.createElement("li");
+		li.textContent = r.doc + " (" + r.score.toFixed(2) + "): " + r.snippet;
+		list.appendChild(li);
+	}
+}
+document.getElementById("q").addEventListener("keydown", e => {
+	if (e.key === "Enter") search();
+});
+</script>
+</body>
+</html>`
+
+// queryInt parses the name query parameter of r as a non-negative integer,
+// falling back to fallback if it is absent or invalid.
+func queryInt(r *http.Request, name string, fallback int) int {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return fallback
+	}
+	return n
+}
+
+// writeJSON encodes v as the response body with the appropriate content
+// type, logging (rather than failing the already-started response) if
+// encoding fails partway through.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to encode JSON response: ", err)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"docindexer/analysis"
+)
+
+// TestHandleSearchZeroLimit verifies that an explicit ?limit=0 falls back to
+// the default page size instead of truncating every result away: queryInt
+// accepts 0 as a validly-parsed limit, so handleSearch has to special-case
+// it rather than passing it straight through to the page[:limit] clamp.
+func TestHandleSearchZeroLimit(t *testing.T) {
+	dir := t.TempDir()
+	mustWriteFile(t, filepath.Join(dir, "a.txt"), "the quick brown fox jumps over the lazy dog")
+
+	engine, err := ReadDirectory(context.Background(), dir, nil, analysis.NewDefaultAnalyzer(), DefaultReadDirectoryOptions())
+	if err != nil {
+		t.Fatalf("ReadDirectory: %v", err)
+	}
+	srv := &server{engine: engine}
+
+	req := httptest.NewRequest("GET", "/search?q=fox&limit=0", nil)
+	w := httptest.NewRecorder()
+	srv.handleSearch(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("handleSearch: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var resp searchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if len(resp.Results) != 1 {
+		t.Errorf("limit=0: got %d results, want the single matching document (limit=0 should fall back to the default)", len(resp.Results))
+	}
+}